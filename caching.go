@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// precompressedVariants lists, in preference order, the sibling file
+// suffix and Content-Encoding value aplos negotiates via Accept-Encoding.
+var precompressedVariants = []struct {
+	suffix   string
+	encoding string
+}{
+	{".br", "br"},
+	{".gz", "gzip"},
+}
+
+// serveFileWithCaching serves a single (non-directory) file. It sets a
+// content-based ETag and the configured Cache-Control header, lets
+// http.ServeContent honor conditional requests (If-None-Match,
+// If-Modified-Since) and Range requests, and transparently serves a
+// precompressed ".br"/".gz" sibling when the client's Accept-Encoding
+// allows it, without changing what the client sees as the resource name.
+func (c *Conf) serveFileWithCaching(w http.ResponseWriter, r *http.Request, fsys http.FileSystem, name string, info os.FileInfo) {
+	// baseETag identifies the underlying file content; each encoding it is
+	// served as gets its own suffixed ETag below, since the plain file and
+	// its .gz/.br siblings are distinct representations that must validate
+	// independently (otherwise a conditional request made with a stale
+	// Accept-Encoding could get a 304 pointing at a cached body for the
+	// wrong encoding).
+	baseETag := fmt.Sprintf("%x-%x", info.ModTime().Unix(), info.Size())
+	if len(c.CacheControl) > 0 {
+		w.Header().Set("Cache-Control", c.CacheControl)
+	}
+
+	// The response content depends on Accept-Encoding (a precompressed
+	// variant may or may not be served) regardless of which branch below
+	// is taken, so Vary must be set on every response, not only when a
+	// precompressed variant is actually chosen.
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	acceptEncoding := r.Header.Get("Accept-Encoding")
+	for _, variant := range precompressedVariants {
+		if !strings.Contains(acceptEncoding, variant.encoding) {
+			continue
+		}
+
+		compressed, err := fsys.Open(name + variant.suffix)
+		if err != nil {
+			continue
+		}
+		defer compressed.Close()
+
+		if ctype := mime.TypeByExtension(filepath.Ext(name)); len(ctype) > 0 {
+			w.Header().Set("Content-Type", ctype)
+		}
+		w.Header().Set("Content-Encoding", variant.encoding)
+		w.Header().Set("ETag", fmt.Sprintf(`"%s-%s"`, baseETag, variant.encoding))
+		http.ServeContent(w, r, info.Name(), info.ModTime(), compressed)
+		return
+	}
+
+	w.Header().Set("ETag", fmt.Sprintf(`"%s"`, baseETag))
+	f, err := fsys.Open(name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConf_serveFileWithCaching_etagAndCacheControl(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "foo.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	c := &Conf{Directory: root, CacheControl: "max-age=60"}
+	fsys := newMaskedFS(root, false, nil)
+	f, err := fsys.Open("/foo.txt")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/foo.txt", nil)
+	rec := httptest.NewRecorder()
+	c.serveFileWithCaching(rec, req, fsys, "/foo.txt", info)
+
+	etag := rec.Header().Get("ETag")
+	if len(etag) == 0 {
+		t.Error("ETag header not set")
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "max-age=60" {
+		t.Errorf("Cache-Control = %q, want max-age=60", got)
+	}
+	if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+		t.Errorf("Vary = %q, want Accept-Encoding", got)
+	}
+
+	// A conditional request with a matching If-None-Match must get a 304.
+	req2 := httptest.NewRequest(http.MethodGet, "/foo.txt", nil)
+	req2.Header.Set("If-None-Match", etag)
+	rec2 := httptest.NewRecorder()
+	c.serveFileWithCaching(rec2, req2, fsys, "/foo.txt", info)
+	if rec2.Code != http.StatusNotModified {
+		t.Errorf("status = %d, want %d", rec2.Code, http.StatusNotModified)
+	}
+}
+
+func TestConf_serveFileWithCaching_precompressedVariant(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "foo.txt"), []byte("plain"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "foo.txt.gz"), []byte("gzipped"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	c := &Conf{Directory: root}
+	fsys := newMaskedFS(root, false, nil)
+	f, err := fsys.Open("/foo.txt")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/foo.txt", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	c.serveFileWithCaching(rec, req, fsys, "/foo.txt", info)
+
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Errorf("Content-Encoding = %q, want gzip", got)
+	}
+	if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+		t.Errorf("Vary = %q, want Accept-Encoding", got)
+	}
+	if got := rec.Body.String(); got != "gzipped" {
+		t.Errorf("body = %q, want gzipped sibling content", got)
+	}
+}
+
+func TestConf_serveFileWithCaching_distinctETagPerEncoding(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "foo.txt"), []byte("plain"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "foo.txt.gz"), []byte("gzipped"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	c := &Conf{Directory: root}
+	fsys := newMaskedFS(root, false, nil)
+	f, err := fsys.Open("/foo.txt")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+
+	plainReq := httptest.NewRequest(http.MethodGet, "/foo.txt", nil)
+	plainRec := httptest.NewRecorder()
+	c.serveFileWithCaching(plainRec, plainReq, fsys, "/foo.txt", info)
+	plainETag := plainRec.Header().Get("ETag")
+
+	gzipReq := httptest.NewRequest(http.MethodGet, "/foo.txt", nil)
+	gzipReq.Header.Set("Accept-Encoding", "gzip")
+	gzipRec := httptest.NewRecorder()
+	c.serveFileWithCaching(gzipRec, gzipReq, fsys, "/foo.txt", info)
+	gzipETag := gzipRec.Header().Get("ETag")
+
+	if plainETag == gzipETag {
+		t.Fatalf("plain and gzip representations share ETag %q, want distinct validators", plainETag)
+	}
+
+	// A stale If-None-Match carried over from the gzip representation must
+	// not short-circuit a request that no longer asks for gzip, or the
+	// client would be handed a 304 pointing at a body it never received.
+	staleReq := httptest.NewRequest(http.MethodGet, "/foo.txt", nil)
+	staleReq.Header.Set("If-None-Match", gzipETag)
+	staleRec := httptest.NewRecorder()
+	c.serveFileWithCaching(staleRec, staleReq, fsys, "/foo.txt", info)
+	if staleRec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d for a stale cross-encoding If-None-Match", staleRec.Code, http.StatusOK)
+	}
+	if got := staleRec.Body.String(); got != "plain" {
+		t.Errorf("body = %q, want plain", got)
+	}
+}
+
+func TestConf_serveFileWithCaching_varySetWithoutVariant(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "foo.txt"), []byte("plain"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	c := &Conf{Directory: root}
+	fsys := newMaskedFS(root, false, nil)
+	f, err := fsys.Open("/foo.txt")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+
+	// No Accept-Encoding sent and no precompressed sibling exists, so the
+	// plain-file fallback path is taken. Vary must still be set, since a
+	// shared cache must not serve this response to a client that does
+	// send Accept-Encoding without re-checking for a compressed variant.
+	req := httptest.NewRequest(http.MethodGet, "/foo.txt", nil)
+	rec := httptest.NewRecorder()
+	c.serveFileWithCaching(rec, req, fsys, "/foo.txt", info)
+
+	if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+		t.Errorf("Vary = %q, want Accept-Encoding", got)
+	}
+	if got := rec.Body.String(); got != "plain" {
+		t.Errorf("body = %q, want plain", got)
+	}
+}
+
+func TestConf_serveFileWithCaching_range(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "foo.txt"), []byte("0123456789"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	c := &Conf{Directory: root}
+	fsys := newMaskedFS(root, false, nil)
+	f, err := fsys.Open("/foo.txt")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/foo.txt", nil)
+	req.Header.Set("Range", "bytes=2-4")
+	rec := httptest.NewRecorder()
+	c.serveFileWithCaching(rec, req, fsys, "/foo.txt", info)
+
+	if rec.Code != http.StatusPartialContent {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusPartialContent)
+	}
+	if got := rec.Body.String(); got != "234" {
+		t.Errorf("body = %q, want 234", got)
+	}
+}
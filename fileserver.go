@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var defaultIndexFiles = []string{"index.html"}
+
+// maskedFS wraps http.Dir so that Open and directory listings never reveal
+// dotfiles or paths matching one of the configured deny globs, whether a
+// client requests them directly or via a directory listing.
+type maskedFS struct {
+	inner        http.FileSystem
+	hideDotfiles bool
+	denyGlobs    []string
+}
+
+func newMaskedFS(root string, hideDotfiles bool, denyGlobs []string) *maskedFS {
+	return &maskedFS{inner: http.Dir(root), hideDotfiles: hideDotfiles, denyGlobs: denyGlobs}
+}
+
+func (fsys *maskedFS) denied(name string) bool {
+	for _, segment := range strings.Split(path.Clean("/"+filepath.ToSlash(name)), "/") {
+		if len(segment) == 0 {
+			continue
+		}
+		if fsys.hideDotfiles && strings.HasPrefix(segment, ".") {
+			return true
+		}
+		for _, glob := range fsys.denyGlobs {
+			if ok, _ := path.Match(glob, segment); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (fsys *maskedFS) Open(name string) (http.File, error) {
+	if fsys.denied(name) {
+		return nil, fs.ErrNotExist
+	}
+
+	f, err := fsys.inner.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &maskedFile{File: f, fsys: fsys}, nil
+}
+
+// maskedFile filters denied entries out of directory listings served
+// through http.FileServer.
+type maskedFile struct {
+	http.File
+	fsys *maskedFS
+}
+
+func (f *maskedFile) Readdir(count int) ([]os.FileInfo, error) {
+	entries, err := f.File.Readdir(count)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := entries[:0]
+	for _, entry := range entries {
+		if !f.fsys.denied(entry.Name()) {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered, nil
+}
+
+// fileHandler builds the handler serving conf.Directory: it honors a
+// configurable list of index filenames, can disable directory listings
+// outright, renders listings as JSON on request, and never exposes
+// dotfiles or deny-listed paths.
+func (c *Conf) fileHandler() http.Handler {
+	fsys := newMaskedFS(c.Directory, c.HideDotfiles, c.DenyGlobs)
+	fileServer := http.FileServer(fsys)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upath := r.URL.Path
+		if !strings.HasPrefix(upath, "/") {
+			upath = "/" + upath
+		}
+		cleanPath := path.Clean(upath)
+
+		f, err := fsys.Open(cleanPath)
+		if err != nil {
+			fileServer.ServeHTTP(w, r)
+			return
+		}
+		defer f.Close()
+
+		info, err := f.Stat()
+		if err != nil {
+			fileServer.ServeHTTP(w, r)
+			return
+		}
+		if !info.IsDir() {
+			c.serveFileWithCaching(w, r, fsys, cleanPath, info)
+			return
+		}
+
+		if indexPath, ok := c.findIndexFile(cleanPath); ok {
+			indexFile, ferr := fsys.Open(indexPath)
+			if ferr == nil {
+				defer indexFile.Close()
+				if indexInfo, serr := indexFile.Stat(); serr == nil {
+					c.serveFileWithCaching(w, r, fsys, indexPath, indexInfo)
+					return
+				}
+			}
+		}
+
+		if c.DisableListing {
+			http.NotFound(w, r)
+			return
+		}
+
+		if wantsJSONListing(r) {
+			serveJSONListing(w, f)
+			return
+		}
+
+		fileServer.ServeHTTP(w, r)
+	})
+}
+
+// findIndexFile reports whether one of c.IndexFiles exists in the
+// directory identified by the url path dirPath, returning the url path to
+// serve it at.
+func (c *Conf) findIndexFile(dirPath string) (string, bool) {
+	for _, name := range c.IndexFiles {
+		candidate := path.Join(c.Directory, dirPath, name)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return path.Join(dirPath, name), true
+		}
+	}
+	return "", false
+}
+
+func wantsJSONListing(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+type jsonDirEntry struct {
+	Name    string `json:"name"`
+	Size    int64  `json:"size"`
+	IsDir   bool   `json:"is_dir"`
+	ModTime string `json:"mod_time"`
+}
+
+func serveJSONListing(w http.ResponseWriter, dir http.File) {
+	entries, err := dir.Readdir(-1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	listing := make([]jsonDirEntry, 0, len(entries))
+	for _, entry := range entries {
+		listing = append(listing, jsonDirEntry{
+			Name:    entry.Name(),
+			Size:    entry.Size(),
+			IsDir:   entry.IsDir(),
+			ModTime: entry.ModTime().UTC().Format("2006-01-02T15:04:05Z07:00"),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(listing)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMaskedFS_denied(t *testing.T) {
+	tests := []struct {
+		name         string
+		hideDotfiles bool
+		denyGlobs    []string
+		path         string
+		want         bool
+	}{
+		{
+			name:         "visible file",
+			hideDotfiles: true,
+			path:         "/foo.txt",
+			want:         false,
+		},
+		{
+			name:         "dotfile hidden",
+			hideDotfiles: true,
+			path:         "/.env",
+			want:         true,
+		},
+		{
+			name:         "dotfile allowed when hiding disabled",
+			hideDotfiles: false,
+			path:         "/.env",
+			want:         false,
+		},
+		{
+			name:         "dotfile in subdirectory hidden",
+			hideDotfiles: true,
+			path:         "/sub/.git/config",
+			want:         true,
+		},
+		{
+			name:      "deny glob matches",
+			denyGlobs: []string{"*.secret"},
+			path:      "/foo.secret",
+			want:      true,
+		},
+		{
+			name:      "deny glob does not match",
+			denyGlobs: []string{"*.secret"},
+			path:      "/foo.txt",
+			want:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := newMaskedFS(t.TempDir(), tt.hideDotfiles, tt.denyGlobs)
+			if got := fsys.denied(tt.path); got != tt.want {
+				t.Errorf("denied(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskedFS_Readdir_filtersDeniedEntries(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"visible.txt", ".hidden", "data.secret"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte("x"), 0644); err != nil {
+			t.Fatalf("WriteFile(%q): %v", name, err)
+		}
+	}
+
+	fsys := newMaskedFS(root, true, []string{"*.secret"})
+	f, err := fsys.Open("/")
+	if err != nil {
+		t.Fatalf("Open(\"/\"): %v", err)
+	}
+	defer f.Close()
+
+	entries, err := f.Readdir(-1)
+	if err != nil {
+		t.Fatalf("Readdir(-1): %v", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if len(names) != 1 || names[0] != "visible.txt" {
+		t.Errorf("Readdir(-1) = %v, want only [visible.txt]", names)
+	}
+}
+
+func TestConf_findIndexFile(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "index.htm"), []byte("hi"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	c := &Conf{Directory: root, IndexFiles: []string{"index.html", "index.htm"}}
+	got, ok := c.findIndexFile("/")
+	if !ok {
+		t.Fatal("findIndexFile(\"/\") = false, want true")
+	}
+	if want := "/index.htm"; got != want {
+		t.Errorf("findIndexFile(\"/\") = %q, want %q", got, want)
+	}
+
+	c = &Conf{Directory: root, IndexFiles: []string{"missing.html"}}
+	if _, ok := c.findIndexFile("/"); ok {
+		t.Error("findIndexFile(\"/\") = true, want false when no configured index file exists")
+	}
+}
+
+func TestConf_fileHandler_jsonListing(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "foo.txt"), []byte("hi"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	c := &Conf{Directory: root, IndexFiles: defaultIndexFiles}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+
+	c.fileHandler().ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+
+	var listing []jsonDirEntry
+	if err := json.Unmarshal(rec.Body.Bytes(), &listing); err != nil {
+		t.Fatalf("could not decode json listing: %v", err)
+	}
+	if len(listing) != 1 || listing[0].Name != "foo.txt" {
+		t.Errorf("listing = %+v, want single entry foo.txt", listing)
+	}
+}
+
+func TestConf_fileHandler_disableListing(t *testing.T) {
+	c := &Conf{Directory: t.TempDir(), DisableListing: true}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+
+	c.fileHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthHandler(t *testing.T) {
+	t.Cleanup(func() { shuttingDown.Store(false) })
+
+	t.Run("reports ok while serving", func(t *testing.T) {
+		shuttingDown.Store(false)
+		rr := httptest.NewRecorder()
+		healthHandler(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("status = %d, want %d", rr.Code, http.StatusOK)
+		}
+	})
+
+	t.Run("reports unavailable while draining", func(t *testing.T) {
+		shuttingDown.Store(true)
+		rr := httptest.NewRecorder()
+		healthHandler(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+		if rr.Code != http.StatusServiceUnavailable {
+			t.Errorf("status = %d, want %d", rr.Code, http.StatusServiceUnavailable)
+		}
+	})
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+const (
+	defaultHttp2MaxConcurrentStreams = 250
+	defaultHttp2MaxFrameSize         = 1 << 20 // 1MiB
+	defaultHttp2IdleTimeout          = 5 * time.Minute
+)
+
+// http2Server builds the tuned golang.org/x/net/http2.Server shared by both
+// the TLS (native ALPN-negotiated h2) and, when enabled, H2C listeners.
+func (c *Conf) http2Server() *http2.Server {
+	return &http2.Server{
+		MaxConcurrentStreams: c.Http2MaxConcurrentStreams,
+		MaxReadFrameSize:     c.Http2MaxFrameSize,
+		IdleTimeout:          c.Http2IdleTimeout,
+	}
+}
+
+// wrapH2C wraps handler so it also accepts cleartext HTTP/2 (h2c) when H2C
+// is enabled. Intended for listeners that terminate in plain text behind a
+// TLS-terminating proxy; TLS listeners get HTTP/2 via ALPN instead and
+// don't need this.
+func (c *Conf) wrapH2C(handler http.Handler) http.Handler {
+	if !c.H2C {
+		return handler
+	}
+	return h2c.NewHandler(handler, c.http2Server())
+}
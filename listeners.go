@@ -0,0 +1,172 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+const defaultUnixSocketMode = 0660
+
+// addressList is a flag.Value that accumulates repeated -a occurrences
+// into a slice instead of overwriting a single string, and discards the
+// env/default-derived values it was seeded with on the first flag.Set
+// call, so passing -a on the command line fully replaces APLOS_ADDR
+// rather than appending to it.
+type addressList struct {
+	values  *[]string
+	started bool
+}
+
+func (a *addressList) String() string {
+	if a.values == nil {
+		return ""
+	}
+	return strings.Join(*a.values, ",")
+}
+
+func (a *addressList) Set(v string) error {
+	if !a.started {
+		*a.values = nil
+		a.started = true
+	}
+	*a.values = append(*a.values, v)
+	return nil
+}
+
+// listenerSpec is a parsed entry of Conf.Addresses, describing the scheme
+// to serve on a listen target with.
+type listenerSpec struct {
+	scheme string // "tcp", "https" or "unix"
+	target string // host:port for tcp/https, filesystem path for unix
+}
+
+// parseListenerSpec parses a single Conf.Addresses entry. A bare
+// "host:port" with no "scheme://" prefix is treated as "tcp://" for
+// backwards compatibility with aplos's original single-address config.
+func parseListenerSpec(raw string) (listenerSpec, error) {
+	if !strings.Contains(raw, "://") {
+		return listenerSpec{scheme: "tcp", target: raw}, nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return listenerSpec{}, fmt.Errorf("invalid address %q: %w", raw, err)
+	}
+
+	switch u.Scheme {
+	case "tcp", "http":
+		return listenerSpec{scheme: "tcp", target: u.Host}, nil
+	case "https":
+		return listenerSpec{scheme: "https", target: u.Host}, nil
+	case "unix":
+		if len(u.Host) > 0 {
+			return listenerSpec{}, fmt.Errorf("invalid unix socket address %q: missing leading slash, use unix:///%s%s", raw, u.Host, u.Path)
+		}
+		path := u.Path
+		if len(path) == 0 {
+			path = u.Opaque
+		}
+		return listenerSpec{scheme: "unix", target: path}, nil
+	default:
+		return listenerSpec{}, fmt.Errorf("unsupported address scheme %q in %q", u.Scheme, raw)
+	}
+}
+
+// boundListener pairs a net.Listener with the *http.Server serving it, so
+// main can fan out Serve calls and later shut every listener down cleanly.
+type boundListener struct {
+	spec       listenerSpec
+	ln         net.Listener
+	server     *http.Server
+	socketPath string // non-empty for unix listeners; removed on shutdown
+}
+
+// buildListeners resolves conf.Addresses into one boundListener per entry,
+// all sharing handler and timeouts, but each with its own net.Listener and
+// *http.Server so a plain HTTP healthcheck listener can coexist with a TLS
+// listener exposed externally.
+func (c *Conf) buildListeners(handler http.Handler, tlsConfig *tls.Config) ([]*boundListener, error) {
+	listeners := make([]*boundListener, 0, len(c.Addresses))
+	for _, raw := range c.Addresses {
+		spec, err := parseListenerSpec(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		bl, err := c.buildListener(spec, handler, tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, bl)
+	}
+	return listeners, nil
+}
+
+func (c *Conf) buildListener(spec listenerSpec, handler http.Handler, tlsConfig *tls.Config) (*boundListener, error) {
+	server := &http.Server{
+		Handler:           handler,
+		IdleTimeout:       secondsToDuration(c.IdleTimeoutSec),
+		ReadTimeout:       secondsToDuration(c.ReadTimeoutSec),
+		WriteTimeout:      secondsToDuration(c.WriteTimeoutSec),
+		ReadHeaderTimeout: secondsToDuration(c.ReadHeaderTimeoutSec),
+	}
+	server.RegisterOnShutdown(func() {
+		slog.Info("Draining in-flight requests", "scheme", spec.scheme, "addr", spec.target)
+	})
+
+	switch spec.scheme {
+	case "tcp":
+		ln, err := net.Listen("tcp", spec.target)
+		if err != nil {
+			return nil, fmt.Errorf("could not listen on %q: %w", spec.target, err)
+		}
+		server.Addr = spec.target
+		server.Handler = c.wrapH2C(handler)
+		return &boundListener{spec: spec, ln: ln, server: server}, nil
+
+	case "https":
+		if tlsConfig == nil {
+			return nil, fmt.Errorf("address %q requires tls cert and key to be configured", spec.target)
+		}
+		ln, err := net.Listen("tcp", spec.target)
+		if err != nil {
+			return nil, fmt.Errorf("could not listen on %q: %w", spec.target, err)
+		}
+		server.Addr = spec.target
+		server.TLSConfig = tlsConfig
+		if err := http2.ConfigureServer(server, c.http2Server()); err != nil {
+			_ = ln.Close()
+			return nil, fmt.Errorf("could not configure http2 for %q: %w", spec.target, err)
+		}
+		return &boundListener{spec: spec, ln: tls.NewListener(ln, tlsConfig), server: server}, nil
+
+	case "unix":
+		_ = os.Remove(spec.target)
+		ln, err := net.Listen("unix", spec.target)
+		if err != nil {
+			return nil, fmt.Errorf("could not listen on unix socket %q: %w", spec.target, err)
+		}
+		if err := os.Chmod(spec.target, c.UnixSocketMode); err != nil {
+			_ = ln.Close()
+			return nil, fmt.Errorf("could not chmod unix socket %q: %w", spec.target, err)
+		}
+		server.Handler = c.wrapH2C(handler)
+		return &boundListener{spec: spec, ln: ln, server: server, socketPath: spec.target}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported address scheme %q", spec.scheme)
+	}
+}
+
+func secondsToDuration(seconds int) time.Duration {
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseListenerSpec(t *testing.T) {
+	tests := []struct {
+		name       string
+		raw        string
+		wantScheme string
+		wantTarget string
+		wantErr    bool
+	}{
+		{
+			name:       "bare host:port defaults to tcp",
+			raw:        "127.0.0.1:8080",
+			wantScheme: "tcp",
+			wantTarget: "127.0.0.1:8080",
+		},
+		{
+			name:       "explicit tcp scheme",
+			raw:        "tcp://127.0.0.1:8080",
+			wantScheme: "tcp",
+			wantTarget: "127.0.0.1:8080",
+		},
+		{
+			name:       "https scheme",
+			raw:        "https://0.0.0.0:8443",
+			wantScheme: "https",
+			wantTarget: "0.0.0.0:8443",
+		},
+		{
+			name:       "unix scheme with triple slash",
+			raw:        "unix:///run/aplos.sock",
+			wantScheme: "unix",
+			wantTarget: "/run/aplos.sock",
+		},
+		{
+			name:    "unix scheme missing leading slash is rejected",
+			raw:     "unix://run/aplos.sock",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported scheme",
+			raw:     "ftp://127.0.0.1:21",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseListenerSpec(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseListenerSpec(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.scheme != tt.wantScheme || got.target != tt.wantTarget {
+				t.Errorf("parseListenerSpec(%q) = %+v, want scheme=%q target=%q", tt.raw, got, tt.wantScheme, tt.wantTarget)
+			}
+		})
+	}
+}
+
+func TestAddressList_Set(t *testing.T) {
+	values := []string{"seeded-from-env:1"}
+	list := &addressList{values: &values}
+
+	if err := list.Set("tcp://127.0.0.1:8080"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := list.Set("unix:///run/aplos.sock"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	want := []string{"tcp://127.0.0.1:8080", "unix:///run/aplos.sock"}
+	if len(values) != len(want) {
+		t.Fatalf("values = %v, want %v", values, want)
+	}
+	for i := range want {
+		if values[i] != want[i] {
+			t.Errorf("values[%d] = %q, want %q", i, values[i], want[i])
+		}
+	}
+}
+
+func TestConf_buildListener(t *testing.T) {
+	c := &Conf{IdleTimeoutSec: 30, ReadTimeoutSec: 30, WriteTimeoutSec: 30, ReadHeaderTimeoutSec: 30}
+	handler := http.NotFoundHandler()
+
+	t.Run("tcp", func(t *testing.T) {
+		bl, err := c.buildListener(listenerSpec{scheme: "tcp", target: "127.0.0.1:0"}, handler, nil)
+		if err != nil {
+			t.Fatalf("buildListener: %v", err)
+		}
+		defer bl.ln.Close()
+		if bl.spec.scheme != "tcp" {
+			t.Errorf("spec.scheme = %q, want tcp", bl.spec.scheme)
+		}
+	})
+
+	t.Run("https without tls config", func(t *testing.T) {
+		if _, err := c.buildListener(listenerSpec{scheme: "https", target: "127.0.0.1:0"}, handler, nil); err == nil {
+			t.Error("buildListener() error = nil, want error when tlsConfig is nil")
+		}
+	})
+
+	t.Run("https with tls config", func(t *testing.T) {
+		bl, err := c.buildListener(listenerSpec{scheme: "https", target: "127.0.0.1:0"}, handler, &tls.Config{})
+		if err != nil {
+			t.Fatalf("buildListener: %v", err)
+		}
+		defer bl.ln.Close()
+	})
+
+	t.Run("unix", func(t *testing.T) {
+		sockPath := filepath.Join(t.TempDir(), "aplos.sock")
+		c := &Conf{IdleTimeoutSec: 30, ReadTimeoutSec: 30, WriteTimeoutSec: 30, ReadHeaderTimeoutSec: 30, UnixSocketMode: 0600}
+		bl, err := c.buildListener(listenerSpec{scheme: "unix", target: sockPath}, handler, nil)
+		if err != nil {
+			t.Fatalf("buildListener: %v", err)
+		}
+		defer bl.ln.Close()
+
+		info, err := os.Stat(sockPath)
+		if err != nil {
+			t.Fatalf("Stat(%q): %v", sockPath, err)
+		}
+		if got := info.Mode().Perm(); got != 0600 {
+			t.Errorf("socket mode = %v, want 0600", got)
+		}
+		if bl.socketPath != sockPath {
+			t.Errorf("socketPath = %q, want %q", bl.socketPath, sockPath)
+		}
+	})
+
+	t.Run("unsupported scheme", func(t *testing.T) {
+		if _, err := c.buildListener(listenerSpec{scheme: "ftp", target: "x"}, handler, nil); err == nil {
+			t.Error("buildListener() error = nil, want error for unsupported scheme")
+		}
+	})
+}
@@ -14,8 +14,11 @@ import (
 	"os/signal"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 const (
@@ -27,6 +30,8 @@ const (
 	defaultReadTimeoutSec       = 60
 	defaultWriteTimeoutSec      = 1800
 	defaultReadHeaderTimeoutSec = 30
+
+	defaultShutdownTimeout = 10 * time.Second
 )
 
 var (
@@ -35,11 +40,29 @@ var (
 )
 
 type Conf struct {
-	Address             string
+	Addresses           []string
 	Directory           string
 	TlsCertFile         string
 	TlsKeyFile          string
 	HealthcheckEndpoint string
+	TlsRefreshInterval  time.Duration
+	TlsClientCaFile     string
+	TlsAllowedClients   []string
+	UnixSocketMode      os.FileMode
+
+	H2C                       bool
+	Http2MaxConcurrentStreams uint32
+	Http2MaxFrameSize         uint32
+	Http2IdleTimeout          time.Duration
+
+	MetricsAddr string
+
+	DisableListing  bool
+	IndexFiles      []string
+	HideDotfiles    bool
+	DenyGlobs       []string
+	CacheControl    string
+	ShutdownTimeout time.Duration
 
 	IdleTimeoutSec       int
 	ReadTimeoutSec       int
@@ -48,12 +71,32 @@ type Conf struct {
 }
 
 func (c *Conf) Validate() error {
-	_, err := net.ResolveTCPAddr("tcp", c.Address)
-	if err != nil {
-		return fmt.Errorf("invalid listen address provided: %w", err)
+	if len(c.Addresses) == 0 {
+		return errors.New("at least one listen address must be configured")
+	}
+
+	for _, raw := range c.Addresses {
+		spec, err := parseListenerSpec(raw)
+		if err != nil {
+			return err
+		}
+
+		if spec.scheme == "unix" {
+			if len(spec.target) == 0 {
+				return fmt.Errorf("invalid unix socket address %q", raw)
+			}
+			continue
+		}
+
+		if _, err := net.ResolveTCPAddr("tcp", spec.target); err != nil {
+			return fmt.Errorf("invalid listen address %q: %w", raw, err)
+		}
+		if spec.scheme == "https" && !c.UseTls() {
+			return fmt.Errorf("address %q requires tls cert and key to be configured", raw)
+		}
 	}
 
-	_, err = os.Stat(c.Directory)
+	_, err := os.Stat(c.Directory)
 	if err != nil {
 		return fmt.Errorf("directory %q does not exist", c.Directory)
 	}
@@ -70,6 +113,24 @@ func (c *Conf) Validate() error {
 		return errors.New("timeout must be > 0")
 	}
 
+	if c.TlsRefreshInterval <= 0 {
+		return errors.New("tls refresh interval must be > 0")
+	}
+
+	if c.ShutdownTimeout <= 0 {
+		return errors.New("shutdown timeout must be > 0")
+	}
+
+	if len(c.TlsClientCaFile) > 0 && !c.UseTls() {
+		return errors.New("tls client ca file requires tls cert and key to be configured")
+	}
+
+	if len(c.MetricsAddr) > 0 {
+		if _, err := net.ResolveTCPAddr("tcp", c.MetricsAddr); err != nil {
+			return fmt.Errorf("invalid metrics address %q: %w", c.MetricsAddr, err)
+		}
+	}
+
 	return nil
 }
 
@@ -77,32 +138,27 @@ func (c *Conf) UseTls() bool {
 	return len(c.TlsCertFile) > 0 && len(c.TlsKeyFile) > 0
 }
 
-func (c *Conf) getTlsConf() (*tls.Config, error) {
+// getTlsConf builds the server tls.Config and, when TLS is enabled, the
+// certReloader backing it. The reloader eagerly loads the keypair once so
+// startup fails fast if the cert/key files are missing or unreadable,
+// instead of only surfacing the error on the first ClientHello.
+func (c *Conf) getTlsConf() (*tls.Config, *certReloader, error) {
 	if !c.UseTls() {
-		return nil, nil
+		return nil, nil, nil
 	}
 
-	tlsConfig := &tls.Config{
-		MinVersion:     tls.VersionTLS13,
-		GetCertificate: c.loadCert,
-	}
-
-	// don't wait for lazy loading the tls keypair when the first request hits the server to
-	// verify whether the files exist and are readable or not.
-	_, err := c.loadCert(nil)
+	reloader, err := newCertReloader(c.TlsCertFile, c.TlsKeyFile, c.TlsClientCaFile)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return tlsConfig, nil
-}
-
-func (c *Conf) loadCert(info *tls.ClientHelloInfo) (*tls.Certificate, error) {
-	certificate, err := tls.LoadX509KeyPair(c.TlsCertFile, c.TlsKeyFile)
-	if err != nil {
-		slog.Error("user-defined client certificates could not be loaded", "error", err)
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS13,
+		GetCertificate: reloader.getCertificate,
 	}
-	return &certificate, err
+	c.getMtlsConf(tlsConfig, reloader)
+
+	return tlsConfig, reloader, nil
 }
 
 func envOrDefaultInt(key string, defaultVal int) int {
@@ -128,32 +184,170 @@ func envOrDefault(key, defaultVal string) string {
 	return val
 }
 
+func envOrDefaultList(key string, defaultVal []string) []string {
+	val := os.Getenv(key)
+	if len(val) == 0 {
+		return defaultVal
+	}
+
+	var list []string
+	for _, entry := range strings.Split(val, ",") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) > 0 {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+func envOrDefaultBool(key string, defaultVal bool) bool {
+	val := os.Getenv(key)
+	if len(val) == 0 {
+		return defaultVal
+	}
+
+	converted, err := strconv.ParseBool(val)
+	if err != nil {
+		slog.Warn("could not parse bool", "var", key, "val", val)
+		return defaultVal
+	}
+
+	return converted
+}
+
+func envOrDefaultFileMode(key string, defaultVal os.FileMode) os.FileMode {
+	val := os.Getenv(key)
+	if len(val) == 0 {
+		return defaultVal
+	}
+
+	converted, err := strconv.ParseUint(val, 8, 32)
+	if err != nil {
+		slog.Warn("could not parse file mode", "var", key, "val", val)
+		return defaultVal
+	}
+
+	return os.FileMode(converted)
+}
+
+func envOrDefaultDuration(key string, defaultVal time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if len(val) == 0 {
+		return defaultVal
+	}
+
+	converted, err := time.ParseDuration(val)
+	if err != nil {
+		slog.Warn("could not parse duration", "var", key, "val", val)
+		return defaultVal
+	}
+
+	return converted
+}
+
 func getConf() Conf {
 	conf := Conf{
-		Address:              envOrDefault("APLOS_ADDR", defaultAddr),
-		Directory:            envOrDefault("APLOS_DIRECTORY", defaultDir),
-		TlsCertFile:          envOrDefault("APLOS_TLS_CRT_FILE", ""),
-		TlsKeyFile:           envOrDefault("APLOS_TLS_KEY_FILE", ""),
-		HealthcheckEndpoint:  envOrDefault("APLOS_HEALTHCHECK_ENDPOINT", defaultHealthcheckEndpoint),
-		IdleTimeoutSec:       envOrDefaultInt("APLOS_TIMEOUT_IDLE", defaultIdleTimeoutSec),
-		ReadHeaderTimeoutSec: envOrDefaultInt("APLOS_TIMEOUT_READ_HEADER", defaultReadHeaderTimeoutSec),
-		ReadTimeoutSec:       envOrDefaultInt("APLOS_TIMEOUT_READ", defaultReadTimeoutSec),
-		WriteTimeoutSec:      envOrDefaultInt("APLOS_TIMEOUT_WRITE", defaultWriteTimeoutSec),
-	}
-
-	flag.StringVar(&conf.Address, "a", conf.Address, "The address to run the server on")
+		Addresses:                 envOrDefaultList("APLOS_ADDR", []string{defaultAddr}),
+		Directory:                 envOrDefault("APLOS_DIRECTORY", defaultDir),
+		TlsCertFile:               envOrDefault("APLOS_TLS_CRT_FILE", ""),
+		TlsKeyFile:                envOrDefault("APLOS_TLS_KEY_FILE", ""),
+		HealthcheckEndpoint:       envOrDefault("APLOS_HEALTHCHECK_ENDPOINT", defaultHealthcheckEndpoint),
+		TlsRefreshInterval:        envOrDefaultDuration("APLOS_TLS_REFRESH_INTERVAL", defaultTlsRefreshInterval),
+		TlsClientCaFile:           envOrDefault("APLOS_TLS_CLIENT_CA_FILE", ""),
+		TlsAllowedClients:         envOrDefaultList("APLOS_TLS_ALLOWED_CLIENTS", nil),
+		UnixSocketMode:            envOrDefaultFileMode("APLOS_UNIX_SOCKET_MODE", defaultUnixSocketMode),
+		H2C:                       envOrDefaultBool("APLOS_H2C", false),
+		Http2MaxConcurrentStreams: uint32(envOrDefaultInt("APLOS_HTTP2_MAX_CONCURRENT_STREAMS", defaultHttp2MaxConcurrentStreams)),
+		Http2MaxFrameSize:         uint32(envOrDefaultInt("APLOS_HTTP2_MAX_FRAME_SIZE", defaultHttp2MaxFrameSize)),
+		Http2IdleTimeout:          envOrDefaultDuration("APLOS_HTTP2_IDLE_TIMEOUT", defaultHttp2IdleTimeout),
+		MetricsAddr:               envOrDefault("APLOS_METRICS_ADDR", ""),
+		DisableListing:            envOrDefaultBool("APLOS_DISABLE_LISTING", false),
+		IndexFiles:                envOrDefaultList("APLOS_INDEX_FILES", defaultIndexFiles),
+		HideDotfiles:              envOrDefaultBool("APLOS_HIDE_DOTFILES", true),
+		DenyGlobs:                 envOrDefaultList("APLOS_DENY_GLOBS", nil),
+		CacheControl:              envOrDefault("APLOS_CACHE_CONTROL", ""),
+		ShutdownTimeout:           envOrDefaultDuration("APLOS_SHUTDOWN_TIMEOUT", defaultShutdownTimeout),
+		IdleTimeoutSec:            envOrDefaultInt("APLOS_TIMEOUT_IDLE", defaultIdleTimeoutSec),
+		ReadHeaderTimeoutSec:      envOrDefaultInt("APLOS_TIMEOUT_READ_HEADER", defaultReadHeaderTimeoutSec),
+		ReadTimeoutSec:            envOrDefaultInt("APLOS_TIMEOUT_READ", defaultReadTimeoutSec),
+		WriteTimeoutSec:           envOrDefaultInt("APLOS_TIMEOUT_WRITE", defaultWriteTimeoutSec),
+	}
+
+	flag.Var(&addressList{values: &conf.Addresses}, "a", "The address to run the server on, e.g. tcp://127.0.0.1:8080, https://0.0.0.0:8443 or unix:///run/aplos.sock. Repeatable to bind several listeners.")
 	flag.StringVar(&conf.Directory, "d", conf.Directory, "The directory to serve")
 	flag.StringVar(&conf.TlsCertFile, "c", conf.TlsCertFile, "File that contains the TLS certificate")
 	flag.StringVar(&conf.TlsKeyFile, "k", conf.TlsCertFile, "File that contains the TLS private key")
 	flag.StringVar(&conf.HealthcheckEndpoint, "p", conf.HealthcheckEndpoint, "Endpoint where to expose the healthcheck handler. Set to \"\" to disable the health check handler.")
+	flag.DurationVar(&conf.TlsRefreshInterval, "tls-refresh", conf.TlsRefreshInterval, "Interval to re-read the tls cert/key files from disk and swap in the new keypair")
+	flag.StringVar(&conf.TlsClientCaFile, "tls-client-ca", conf.TlsClientCaFile, "File that contains the client CA bundle to enable mutual TLS. Set to \"\" to disable mutual TLS.")
+	allowedClients := flag.String("tls-allowed-clients", strings.Join(conf.TlsAllowedClients, ","), "Comma-separated list of client certificate CNs/SANs allowed to connect when mutual TLS is enabled. Leave empty to allow any client presenting a certificate signed by the configured CA.")
+	unixSocketMode := flag.String("unix-socket-mode", fmt.Sprintf("%o", conf.UnixSocketMode), "File mode (octal) applied to unix socket listeners")
+	flag.BoolVar(&conf.H2C, "h2c", conf.H2C, "Accept cleartext HTTP/2 (h2c) on non-TLS listeners, for use behind a TLS-terminating proxy")
+	http2MaxConcurrentStreams := flag.Int("http2-max-concurrent-streams", int(conf.Http2MaxConcurrentStreams), "Maximum number of concurrent HTTP/2 streams per connection")
+	http2MaxFrameSize := flag.Int("http2-max-frame-size", int(conf.Http2MaxFrameSize), "Maximum HTTP/2 frame size advertised to clients")
+	flag.DurationVar(&conf.Http2IdleTimeout, "http2-idle-timeout", conf.Http2IdleTimeout, "Idle timeout for HTTP/2 connections")
+	flag.StringVar(&conf.MetricsAddr, "metrics-addr", conf.MetricsAddr, "Address to expose Prometheus metrics and, if configured, the healthcheck on a separate admin listener. Set to \"\" to disable.")
+	flag.BoolVar(&conf.DisableListing, "disable-listing", conf.DisableListing, "Disable automatic directory listings")
+	indexFiles := flag.String("index-files", strings.Join(conf.IndexFiles, ","), "Comma-separated list of index filenames served for directory requests, in priority order")
+	flag.BoolVar(&conf.HideDotfiles, "hide-dotfiles", conf.HideDotfiles, "Hide dotfiles from listings and direct access")
+	denyGlobs := flag.String("deny-globs", strings.Join(conf.DenyGlobs, ","), "Comma-separated list of glob patterns hidden from listings and direct access")
+	flag.StringVar(&conf.CacheControl, "cache-control", conf.CacheControl, "Value of the Cache-Control header set on served files. Leave empty to omit the header.")
+	flag.DurationVar(&conf.ShutdownTimeout, "shutdown-timeout", conf.ShutdownTimeout, "Maximum time to wait for in-flight requests to finish during a graceful shutdown")
 	flag.IntVar(&conf.IdleTimeoutSec, "idle-timeout", conf.IdleTimeoutSec, "Set the idle timeout in seconds")
 	flag.IntVar(&conf.ReadHeaderTimeoutSec, "read-header-timeout", conf.ReadHeaderTimeoutSec, "Set the read-header timeout in seconds")
 	flag.IntVar(&conf.ReadTimeoutSec, "read-timeout", conf.ReadTimeoutSec, "Set the read timeout in seconds")
 	flag.IntVar(&conf.WriteTimeoutSec, "write-timeout", conf.WriteTimeoutSec, "Set the write timeout in seconds")
 	flag.Parse()
+
+	conf.TlsAllowedClients = nil
+	for _, entry := range strings.Split(*allowedClients, ",") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) > 0 {
+			conf.TlsAllowedClients = append(conf.TlsAllowedClients, entry)
+		}
+	}
+
+	if mode, err := strconv.ParseUint(*unixSocketMode, 8, 32); err == nil {
+		conf.UnixSocketMode = os.FileMode(mode)
+	} else {
+		slog.Warn("could not parse unix socket mode, keeping previous value", "val", *unixSocketMode)
+	}
+
+	conf.Http2MaxConcurrentStreams = uint32(*http2MaxConcurrentStreams)
+	conf.Http2MaxFrameSize = uint32(*http2MaxFrameSize)
+
+	conf.IndexFiles = nil
+	for _, entry := range strings.Split(*indexFiles, ",") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) > 0 {
+			conf.IndexFiles = append(conf.IndexFiles, entry)
+		}
+	}
+
+	conf.DenyGlobs = nil
+	for _, entry := range strings.Split(*denyGlobs, ",") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) > 0 {
+			conf.DenyGlobs = append(conf.DenyGlobs, entry)
+		}
+	}
+
 	return conf
 }
 
+// shuttingDown flips to true once a shutdown signal is received, so the
+// healthcheck can start reporting 503 before listeners actually stop
+// accepting connections, giving load balancers time to drain traffic away.
+var shuttingDown atomic.Bool
+
+func healthHandler(w http.ResponseWriter, _ *http.Request) {
+	if shuttingDown.Load() {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	_, _ = w.Write([]byte("OK"))
+}
+
 func main() {
 	slog.Info("Starting aplos", "version", BuildVersion, "commit", CommitHash)
 	conf := getConf()
@@ -162,48 +356,84 @@ func main() {
 	}
 
 	mux := http.NewServeMux()
-	mux.Handle("/", http.FileServer(http.Dir(conf.Directory)))
-	if len(conf.HealthcheckEndpoint) > 0 {
-		mux.HandleFunc(conf.HealthcheckEndpoint, func(w http.ResponseWriter, req *http.Request) {
-			_, _ = w.Write([]byte("OK"))
-		})
+	mux.Handle("/", conf.fileHandler())
+
+	// With an admin listener configured, the healthcheck moves off the
+	// public data plane and onto the admin plane alongside /metrics.
+	var adminMux *http.ServeMux
+	if len(conf.MetricsAddr) > 0 {
+		adminMux = http.NewServeMux()
+		adminMux.Handle("/metrics", promhttp.Handler())
+		if len(conf.HealthcheckEndpoint) > 0 {
+			adminMux.HandleFunc(conf.HealthcheckEndpoint, healthHandler)
+		}
+	} else if len(conf.HealthcheckEndpoint) > 0 {
+		mux.HandleFunc(conf.HealthcheckEndpoint, healthHandler)
 	}
 
-	tlsConfig, err := conf.getTlsConf()
+	tlsConfig, certReloader, err := conf.getTlsConf()
 	if err != nil {
 		log.Fatalf("invalid tls config: %v", err)
 	}
 
-	server := http.Server{
-		Addr:              conf.Address,
-		Handler:           mux,
-		TLSConfig:         tlsConfig,
-		IdleTimeout:       time.Duration(conf.IdleTimeoutSec) * time.Second,
-		ReadTimeout:       time.Duration(conf.ReadTimeoutSec) * time.Second,
-		WriteTimeout:      time.Duration(conf.WriteTimeoutSec) * time.Second,
-		ReadHeaderTimeout: time.Duration(conf.ReadHeaderTimeoutSec) * time.Second,
-	}
-
-	go func() {
-		var err error
-		if conf.UseTls() {
-			slog.Info("Starting TLS server", "directory", conf.Directory, "addr", conf.Address)
-			err = server.ListenAndServeTLS("", "")
-		} else {
-			slog.Info("Starting server", "directory", conf.Directory, "addr", conf.Address)
-			err = server.ListenAndServe()
-		}
+	reloadCtx, stopReload := context.WithCancel(context.Background())
+	defer stopReload()
+	if certReloader != nil {
+		go certReloader.watch(reloadCtx, conf.TlsRefreshInterval)
+	}
 
-		if err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Fatalf("can not start server: %v", err)
+	handler := withAccessLog(withMetrics(mux))
+	listeners, err := conf.buildListeners(handler, tlsConfig)
+	if err != nil {
+		log.Fatalf("could not set up listeners: %v", err)
+	}
+
+	if adminMux != nil {
+		adminListener, err := conf.buildListener(listenerSpec{scheme: "tcp", target: conf.MetricsAddr}, adminMux, nil)
+		if err != nil {
+			log.Fatalf("could not set up admin listener: %v", err)
 		}
-	}()
+		listeners = append(listeners, adminListener)
+	}
+
+	for _, bl := range listeners {
+		bl := bl
+		go func() {
+			slog.Info("Starting listener", "scheme", bl.spec.scheme, "addr", bl.spec.target, "directory", conf.Directory)
+			if err := bl.server.Serve(bl.ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Fatalf("listener %s://%s failed: %v", bl.spec.scheme, bl.spec.target, err)
+			}
+		}()
+	}
 
 	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-	<-sigs
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	for sig := range sigs {
+		if sig == syscall.SIGHUP {
+			slog.Info("Caught SIGHUP, reloading tls certificates")
+			if certReloader != nil {
+				if err := certReloader.reloadCert(); err != nil {
+					slog.Error("could not reload tls certificate", "err", err)
+				}
+				if len(conf.TlsClientCaFile) > 0 {
+					if err := certReloader.reloadClientCAs(); err != nil {
+						slog.Error("could not reload tls client ca bundle", "err", err)
+					}
+				}
+			}
+			continue
+		}
+		break
+	}
+
 	slog.Info("Caught signal, shutting down")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	shuttingDown.Store(true)
+	ctx, cancel := context.WithTimeout(context.Background(), conf.ShutdownTimeout)
 	defer cancel()
-	_ = server.Shutdown(ctx)
+	for _, bl := range listeners {
+		_ = bl.server.Shutdown(ctx)
+		if len(bl.socketPath) > 0 {
+			_ = os.Remove(bl.socketPath)
+		}
+	}
 }
@@ -1,6 +1,9 @@
 package main
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestConf_Validate(t *testing.T) {
 	type fields struct {
@@ -9,6 +12,8 @@ func TestConf_Validate(t *testing.T) {
 		TlsCertFile          string
 		TlsKeyFile           string
 		HealthHandlerPattern string
+		TlsRefreshInterval   time.Duration
+		ShutdownTimeout      time.Duration
 		IdleTimeoutSec       int
 		ReadTimeoutSec       int
 		WriteTimeoutSec      int
@@ -25,6 +30,8 @@ func TestConf_Validate(t *testing.T) {
 				Address:              ":8080",
 				Directory:            "/tmp",
 				HealthHandlerPattern: "/_handler",
+				TlsRefreshInterval:   30 * time.Second,
+				ShutdownTimeout:      30 * time.Second,
 				IdleTimeoutSec:       30,
 				ReadTimeoutSec:       30,
 				WriteTimeoutSec:      30,
@@ -38,6 +45,8 @@ func TestConf_Validate(t *testing.T) {
 				Address:              "8080",
 				Directory:            "/tmp",
 				HealthHandlerPattern: "/_handler",
+				TlsRefreshInterval:   30 * time.Second,
+				ShutdownTimeout:      30 * time.Second,
 				IdleTimeoutSec:       30,
 				ReadTimeoutSec:       30,
 				WriteTimeoutSec:      30,
@@ -51,6 +60,8 @@ func TestConf_Validate(t *testing.T) {
 				Address:              ":8080",
 				Directory:            "/nonexistentfoldertmp",
 				HealthHandlerPattern: "/_handler",
+				TlsRefreshInterval:   30 * time.Second,
+				ShutdownTimeout:      30 * time.Second,
 				IdleTimeoutSec:       30,
 				ReadTimeoutSec:       30,
 				WriteTimeoutSec:      30,
@@ -64,6 +75,38 @@ func TestConf_Validate(t *testing.T) {
 				Address:              ":8080",
 				Directory:            "/tmp",
 				HealthHandlerPattern: "_handler",
+				TlsRefreshInterval:   30 * time.Second,
+				ShutdownTimeout:      30 * time.Second,
+				IdleTimeoutSec:       30,
+				ReadTimeoutSec:       30,
+				WriteTimeoutSec:      30,
+				ReadHeaderTimeoutSec: 30,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid tls refresh interval",
+			fields: fields{
+				Address:              ":8080",
+				Directory:            "/tmp",
+				HealthHandlerPattern: "/_handler",
+				TlsRefreshInterval:   0,
+				ShutdownTimeout:      30 * time.Second,
+				IdleTimeoutSec:       30,
+				ReadTimeoutSec:       30,
+				WriteTimeoutSec:      30,
+				ReadHeaderTimeoutSec: 30,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid shutdown timeout",
+			fields: fields{
+				Address:              ":8080",
+				Directory:            "/tmp",
+				HealthHandlerPattern: "/_handler",
+				TlsRefreshInterval:   30 * time.Second,
+				ShutdownTimeout:      0,
 				IdleTimeoutSec:       30,
 				ReadTimeoutSec:       30,
 				WriteTimeoutSec:      30,
@@ -75,11 +118,13 @@ func TestConf_Validate(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			c := &Conf{
-				Address:              tt.fields.Address,
+				Addresses:            []string{tt.fields.Address},
 				Directory:            tt.fields.Directory,
 				TlsCertFile:          tt.fields.TlsCertFile,
 				TlsKeyFile:           tt.fields.TlsKeyFile,
 				HealthcheckEndpoint:  tt.fields.HealthHandlerPattern,
+				TlsRefreshInterval:   tt.fields.TlsRefreshInterval,
+				ShutdownTimeout:      tt.fields.ShutdownTimeout,
 				IdleTimeoutSec:       tt.fields.IdleTimeoutSec,
 				ReadTimeoutSec:       tt.fields.ReadTimeoutSec,
 				WriteTimeoutSec:      tt.fields.WriteTimeoutSec,
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "aplos_http_requests_total",
+		Help: "Total number of HTTP requests processed, partitioned by method and status code.",
+	}, []string{"method", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "aplos_http_request_duration_seconds",
+		Help:    "Latency of HTTP requests in seconds, partitioned by method and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "status"})
+
+	httpResponseBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "aplos_http_response_bytes",
+		Help: "Total bytes written in HTTP responses, partitioned by method.",
+	}, []string{"method"})
+
+	httpRequestsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "aplos_http_requests_in_flight",
+		Help: "Number of HTTP requests currently being served.",
+	})
+)
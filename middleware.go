@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// statusRecorder wraps an http.ResponseWriter to capture the status code
+// and number of bytes written, since net/http gives handlers no way to
+// observe either after the fact.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// withAccessLog wraps handler with a structured slog access log line per
+// request, including TLS details when the connection is encrypted.
+func withAccessLog(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		handler.ServeHTTP(rec, r)
+
+		attrs := []any{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"bytes", rec.bytes,
+			"duration", time.Since(start),
+			"remote_addr", r.RemoteAddr,
+		}
+		if r.TLS != nil {
+			attrs = append(attrs,
+				"tls_version", tls.VersionName(r.TLS.Version),
+				"tls_cipher", tls.CipherSuiteName(r.TLS.CipherSuite),
+				"tls_sni", r.TLS.ServerName,
+			)
+		}
+		slog.Info("http access", attrs...)
+	})
+}
+
+// withMetrics wraps handler with the Prometheus request counters, latency
+// histogram and in-flight gauge defined in metrics.go.
+func withMetrics(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		httpRequestsInFlight.Inc()
+		defer httpRequestsInFlight.Dec()
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		handler.ServeHTTP(rec, r)
+
+		status := strconv.Itoa(rec.status)
+		httpRequestsTotal.WithLabelValues(r.Method, status).Inc()
+		httpRequestDuration.WithLabelValues(r.Method, status).Observe(time.Since(start).Seconds())
+		httpResponseBytes.WithLabelValues(r.Method).Add(float64(rec.bytes))
+	})
+}
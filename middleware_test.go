@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestStatusRecorder(t *testing.T) {
+	t.Run("captures status and bytes across multiple writes", func(t *testing.T) {
+		rec := &statusRecorder{ResponseWriter: httptest.NewRecorder()}
+		rec.WriteHeader(http.StatusCreated)
+		rec.Write([]byte("hello "))
+		rec.Write([]byte("world"))
+
+		if rec.status != http.StatusCreated {
+			t.Errorf("status = %d, want %d", rec.status, http.StatusCreated)
+		}
+		if rec.bytes != len("hello world") {
+			t.Errorf("bytes = %d, want %d", rec.bytes, len("hello world"))
+		}
+	})
+
+	t.Run("defaults to 200 when WriteHeader is never called", func(t *testing.T) {
+		rec := &statusRecorder{ResponseWriter: httptest.NewRecorder()}
+		rec.Write([]byte("ok"))
+
+		if rec.status != http.StatusOK {
+			t.Errorf("status = %d, want %d", rec.status, http.StatusOK)
+		}
+		if rec.bytes != len("ok") {
+			t.Errorf("bytes = %d, want %d", rec.bytes, len("ok"))
+		}
+	})
+}
+
+func TestWithAccessLog(t *testing.T) {
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("body"))
+	})
+
+	rr := httptest.NewRecorder()
+	withAccessLog(inner).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rr.Code != http.StatusAccepted {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusAccepted)
+	}
+	if rr.Body.String() != "body" {
+		t.Errorf("body = %q, want %q", rr.Body.String(), "body")
+	}
+}
+
+func TestWithMetrics(t *testing.T) {
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("teapot"))
+	})
+
+	rr := httptest.NewRecorder()
+	withMetrics(inner).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/brew", nil))
+
+	if rr.Code != http.StatusTeapot {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusTeapot)
+	}
+
+	status := "418"
+	if got := testutil.ToFloat64(httpRequestsTotal.WithLabelValues(http.MethodGet, status)); got != 1 {
+		t.Errorf("httpRequestsTotal = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(httpResponseBytes.WithLabelValues(http.MethodGet)); got != float64(len("teapot")) {
+		t.Errorf("httpResponseBytes = %v, want %d", got, len("teapot"))
+	}
+	if got := testutil.ToFloat64(httpRequestsInFlight); got != 0 {
+		t.Errorf("httpRequestsInFlight = %v, want 0 after request completes", got)
+	}
+}
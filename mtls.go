@@ -0,0 +1,57 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+)
+
+// verifyAllowedClient returns a tls.Config.VerifyPeerCertificate callback
+// that rejects a client certificate unless its Subject CommonName or one of
+// its DNS SANs appears in allowed. Used to restrict mTLS access to a known
+// set of clients behind a shared, otherwise trusted, CA.
+func verifyAllowedClient(allowed []string) func(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+	allowedSet := make(map[string]struct{}, len(allowed))
+	for _, name := range allowed {
+		allowedSet[name] = struct{}{}
+	}
+
+	return func(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+		for _, chain := range verifiedChains {
+			if len(chain) == 0 {
+				continue
+			}
+			leaf := chain[0]
+			if _, ok := allowedSet[leaf.Subject.CommonName]; ok {
+				return nil
+			}
+			for _, name := range leaf.DNSNames {
+				if _, ok := allowedSet[name]; ok {
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("client certificate is not in the allowed clients list")
+	}
+}
+
+// getMtlsConf wires client certificate verification into tlsConfig when a
+// client CA file has been configured, requiring and verifying client certs
+// against the reloader's currently loaded CA pool. If allowedClients is
+// non-empty, access is further restricted to clients whose CN/SAN matches
+// an entry in the list.
+func (c *Conf) getMtlsConf(tlsConfig *tls.Config, reloader *certReloader) {
+	if len(c.TlsClientCaFile) == 0 {
+		return
+	}
+
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	tlsConfig.GetConfigForClient = func(_ *tls.ClientHelloInfo) (*tls.Config, error) {
+		cfg := tlsConfig.Clone()
+		cfg.ClientCAs = reloader.getClientCAs()
+		if len(c.TlsAllowedClients) > 0 {
+			cfg.VerifyPeerCertificate = verifyAllowedClient(c.TlsAllowedClients)
+		}
+		return cfg, nil
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+)
+
+func TestVerifyAllowedClient(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		cert    *x509.Certificate
+		wantErr bool
+	}{
+		{
+			name:    "allowed CN passes",
+			allowed: []string{"client-a"},
+			cert:    &x509.Certificate{Subject: pkix.Name{CommonName: "client-a"}},
+		},
+		{
+			name:    "allowed SAN passes",
+			allowed: []string{"client-a.internal"},
+			cert:    &x509.Certificate{Subject: pkix.Name{CommonName: "other"}, DNSNames: []string{"client-a.internal"}},
+		},
+		{
+			name:    "disallowed CN/SAN is rejected",
+			allowed: []string{"client-a"},
+			cert:    &x509.Certificate{Subject: pkix.Name{CommonName: "client-b"}, DNSNames: []string{"client-b.internal"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verify := verifyAllowedClient(tt.allowed)
+			err := verify(nil, [][]*x509.Certificate{{tt.cert}})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConf_getMtlsConf(t *testing.T) {
+	reloader := &certReloader{}
+	reloader.clientCAs.Store(x509.NewCertPool())
+
+	t.Run("empty allowlist permits any cert signed by the ca", func(t *testing.T) {
+		c := &Conf{TlsClientCaFile: "ca.pem"}
+		tlsConfig := &tls.Config{}
+		c.getMtlsConf(tlsConfig, reloader)
+
+		if tlsConfig.ClientAuth != tls.RequireAndVerifyClientCert {
+			t.Fatalf("ClientAuth = %v, want RequireAndVerifyClientCert", tlsConfig.ClientAuth)
+		}
+
+		cfg, err := tlsConfig.GetConfigForClient(nil)
+		if err != nil {
+			t.Fatalf("GetConfigForClient: %v", err)
+		}
+		if cfg.VerifyPeerCertificate != nil {
+			t.Error("VerifyPeerCertificate should be nil when no allowlist is configured, so any CA-signed cert is accepted")
+		}
+	})
+
+	t.Run("allowlist rejects a disallowed client", func(t *testing.T) {
+		c := &Conf{TlsClientCaFile: "ca.pem", TlsAllowedClients: []string{"client-a"}}
+		tlsConfig := &tls.Config{}
+		c.getMtlsConf(tlsConfig, reloader)
+
+		cfg, err := tlsConfig.GetConfigForClient(nil)
+		if err != nil {
+			t.Fatalf("GetConfigForClient: %v", err)
+		}
+		if cfg.VerifyPeerCertificate == nil {
+			t.Fatal("VerifyPeerCertificate should be installed when an allowlist is configured")
+		}
+
+		allowed := &x509.Certificate{Subject: pkix.Name{CommonName: "client-a"}}
+		if err := cfg.VerifyPeerCertificate(nil, [][]*x509.Certificate{{allowed}}); err != nil {
+			t.Errorf("allowed client rejected: %v", err)
+		}
+
+		disallowed := &x509.Certificate{Subject: pkix.Name{CommonName: "client-b"}}
+		if err := cfg.VerifyPeerCertificate(nil, [][]*x509.Certificate{{disallowed}}); err == nil {
+			t.Error("disallowed client accepted, want error")
+		}
+	})
+
+	t.Run("a reloaded ca pool takes effect for the next handshake", func(t *testing.T) {
+		c := &Conf{TlsClientCaFile: "ca.pem"}
+		tlsConfig := &tls.Config{}
+		c.getMtlsConf(tlsConfig, reloader)
+
+		cfg1, err := tlsConfig.GetConfigForClient(nil)
+		if err != nil {
+			t.Fatalf("GetConfigForClient: %v", err)
+		}
+		pool1 := cfg1.ClientCAs
+
+		reloader.clientCAs.Store(x509.NewCertPool())
+
+		cfg2, err := tlsConfig.GetConfigForClient(nil)
+		if err != nil {
+			t.Fatalf("GetConfigForClient: %v", err)
+		}
+		pool2 := cfg2.ClientCAs
+
+		if pool1 == pool2 {
+			t.Error("GetConfigForClient returned the same ClientCAs pool after a reload, want the newly reloaded pool")
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+const defaultTlsRefreshInterval = 5 * time.Minute
+
+// certReloader holds the currently active TLS keypair and, optionally, the
+// client CA bundle used for mTLS, periodically refreshing both from disk.
+// If a reload fails, the previously loaded material is kept in place and
+// the failure is only logged, so a bad rollout of a new cert/key pair or CA
+// bundle never takes the server down.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	cert      atomic.Pointer[tls.Certificate]
+	clientCAs atomic.Pointer[x509.CertPool]
+}
+
+func newCertReloader(certFile, keyFile, caFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile, caFile: caFile}
+	if err := r.reloadCert(); err != nil {
+		return nil, err
+	}
+	if len(caFile) > 0 {
+		if err := r.reloadClientCAs(); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *certReloader) reloadCert() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.cert.Store(&cert)
+	return nil
+}
+
+func (r *certReloader) reloadClientCAs() error {
+	raw, err := os.ReadFile(r.caFile)
+	if err != nil {
+		return err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(raw) {
+		return fmt.Errorf("no valid client ca certificates found in %q", r.caFile)
+	}
+
+	r.clientCAs.Store(pool)
+	return nil
+}
+
+func (r *certReloader) getCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+func (r *certReloader) getClientCAs() *x509.CertPool {
+	return r.clientCAs.Load()
+}
+
+// watch reloads the certificate, and the client CA bundle if configured,
+// from disk every interval until ctx is cancelled. A reload error is logged
+// and the previously loaded material keeps serving traffic until the next
+// successful reload.
+func (r *certReloader) watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.reloadCert(); err != nil {
+				slog.Error("could not reload tls certificate, keeping previously loaded certificate", "error", err, "cert", r.certFile, "key", r.keyFile)
+			}
+			if len(r.caFile) > 0 {
+				if err := r.reloadClientCAs(); err != nil {
+					slog.Error("could not reload tls client ca bundle, keeping previously loaded bundle", "error", err, "ca", r.caFile)
+				}
+			}
+		}
+	}
+}
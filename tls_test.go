@@ -0,0 +1,81 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func generateTestCAPEM(t *testing.T, commonName string) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestCertReloader_reloadClientCAs(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, generateTestCAPEM(t, "ca-1"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	r := &certReloader{caFile: caFile}
+	if err := r.reloadClientCAs(); err != nil {
+		t.Fatalf("reloadClientCAs: %v", err)
+	}
+	pool1 := r.getClientCAs()
+	if pool1 == nil {
+		t.Fatal("getClientCAs() = nil after reload")
+	}
+
+	if err := os.WriteFile(caFile, generateTestCAPEM(t, "ca-2"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := r.reloadClientCAs(); err != nil {
+		t.Fatalf("reloadClientCAs: %v", err)
+	}
+	pool2 := r.getClientCAs()
+
+	if pool1 == pool2 {
+		t.Error("reloadClientCAs did not swap in a new pool")
+	}
+}
+
+func TestCertReloader_reloadClientCAs_invalidPEM(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, []byte("not a certificate"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	r := &certReloader{caFile: caFile}
+	if err := r.reloadClientCAs(); err == nil {
+		t.Error("reloadClientCAs() error = nil, want error for invalid PEM content")
+	}
+}